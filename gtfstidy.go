@@ -34,11 +34,15 @@ func main() {
 	useShapeRemeasurer := flag.BoolP("remeasure-shapes", "m", false, "remeasure shapes (filling measurement-holes)")
 	useRedShapeRemover := flag.BoolP("remove-red-shapes", "S", false, "remove shape duplicates")
 	useRedRouteMinimizer := flag.BoolP("remove-red-routes", "R", false, "remove route duplicates")
+	useRedAgencyMinimizer := flag.BoolP("remove-red-agencies", "A", false, "remove agency duplicates")
+	useRedStopMinimizer := flag.BoolP("remove-red-stops", "G", false, "remove stop duplicates")
+	stopMergeDist := flag.Float64("stop-merge-dist", 1.0, "maximum distance in meters between two stops for them to be considered duplicates")
 	useRedServiceMinimizer := flag.BoolP("remove-red-services", "C", false, "remove duplicate services in calendar.txt and calendar_dates.txt")
 	useIDMinimizerNum := flag.BoolP("minimize-ids-num", "i", false, "minimize IDs using numerical IDs (e.g. 144, 145, 146...)")
 	useIDMinimizerChar := flag.BoolP("minimize-ids-char", "d", false, "minimize IDs using character IDs (e.g. abc, abd, abe, abf...)")
 	useServiceMinimizer := flag.BoolP("minimize-services", "c", false, "minimize services by searching for the optimal exception/range coverage")
 	useFrequencyMinimizer := flag.BoolP("minimize-stoptimes", "T", false, "search for frequency patterns in explicit trips and combine them, using a CAP approach")
+	useAttributionsMinimizer := flag.BoolP("minimize-attributions", "", false, "collapse identical attributions that point to the same entity")
 	help := flag.BoolP("help", "?", false, "this message")
 
 	flag.Parse()
@@ -101,6 +105,14 @@ func main() {
 			minzers = append(minzers, processors.RouteDuplicateRemover{})
 		}
 
+		if *useRedAgencyMinimizer {
+			minzers = append(minzers, processors.AgencyDuplicateRemover{})
+		}
+
+		if *useRedStopMinimizer {
+			minzers = append(minzers, processors.StopDuplicateRemover{MaxEqDistance: *stopMergeDist})
+		}
+
 		if *useRedServiceMinimizer {
 			minzers = append(minzers, processors.ServiceDuplicateRemover{})
 		}
@@ -113,6 +125,10 @@ func main() {
 			minzers = append(minzers, processors.FrequencyMinimizer{})
 		}
 
+		if *useAttributionsMinimizer {
+			minzers = append(minzers, processors.AttributionsMinimizer{})
+		}
+
 		if *useIDMinimizerNum {
 			minzers = append(minzers, processors.IDMinimizer{Base: 10})
 		} else if *useIDMinimizerChar {
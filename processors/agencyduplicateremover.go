@@ -0,0 +1,147 @@
+// Copyright 2016 Patrick Brosi
+// Authors: info@patrickbrosi.de
+//
+// Use of this source code is governed by a GPL v2
+// license that can be found in the LICENSE file
+
+package processors
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"os"
+
+	"github.com/patrickbr/gtfsparser"
+	gtfs "github.com/patrickbr/gtfsparser/gtfs"
+	"github.com/patrickbr/gtfstidy/processors/attrutil"
+)
+
+// AgencyDuplicateRemover merges semantically equivalent agencies
+type AgencyDuplicateRemover struct {
+}
+
+// Run this AgencyDuplicateRemover on some feed
+func (m AgencyDuplicateRemover) Run(feed *gtfsparser.Feed) {
+	fmt.Fprintf(os.Stdout, "Removing redundant agencies... ")
+	bef := len(feed.Agencies)
+
+	buckets := make(map[uint64][]*gtfs.Agency, len(feed.Agencies))
+
+	for _, a := range feed.Agencies {
+		h := m.agencyHash(a)
+		buckets[h] = append(buckets[h], a)
+	}
+
+	for _, bucket := range buckets {
+		if len(bucket) < 2 {
+			continue
+		}
+
+		proced := make(map[*gtfs.Agency]bool, len(bucket))
+
+		for _, a := range bucket {
+			if proced[a] {
+				continue
+			}
+
+			eqAgencies := make([]*gtfs.Agency, 0)
+
+			for _, b := range bucket {
+				if a != b && !proced[b] && m.agencyEquals(a, b) {
+					eqAgencies = append(eqAgencies, b)
+				}
+			}
+
+			if len(eqAgencies) > 0 {
+				m.combineAgencies(feed, append(eqAgencies, a))
+
+				for _, b := range eqAgencies {
+					proced[b] = true
+				}
+			}
+
+			proced[a] = true
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "done. (-%d agencies)\n", (bef - len(feed.Agencies)))
+}
+
+// Compute a stable identity hash over the normalized agency tuple
+func (m AgencyDuplicateRemover) agencyHash(a *gtfs.Agency) uint64 {
+	h := fnv.New64a()
+
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s|%s|%s",
+		a.Name, urlString(a.Url), string(a.Timezone), langString(a.Lang), a.Phone, urlString(a.Fare_url), a.Email)
+
+	return h.Sum64()
+}
+
+// Check if two agencies are semantically equal
+func (m AgencyDuplicateRemover) agencyEquals(a *gtfs.Agency, b *gtfs.Agency) bool {
+	return a.Name == b.Name && urlString(a.Url) == urlString(b.Url) && a.Timezone == b.Timezone &&
+		langString(a.Lang) == langString(b.Lang) && a.Phone == b.Phone &&
+		urlString(a.Fare_url) == urlString(b.Fare_url) && a.Email == b.Email
+}
+
+// Combine a slice of equal agencies into a single agency
+func (m AgencyDuplicateRemover) combineAgencies(feed *gtfsparser.Feed, agencies []*gtfs.Agency) {
+	// heuristic: use the agency with the shortest ID as 'reference'
+	ref := agencies[0]
+
+	for _, a := range agencies {
+		if len(a.Id) < len(ref.Id) {
+			ref = a
+		}
+	}
+
+	for _, a := range agencies {
+		if a == ref {
+			continue
+		}
+
+		// repoint the back-reference before merging, so attributions that
+		// survive the merge don't keep dangling pointers to the deleted
+		// agency
+		for _, at := range a.Attributions {
+			at.Agency = ref
+		}
+
+		ref.Attributions = attrutil.MergeAttributions(ref.Attributions, a.Attributions)
+
+		for _, r := range feed.Routes {
+			if r.Agency == a {
+				r.Agency = ref
+			}
+		}
+
+		for _, fa := range feed.FareAttributes {
+			if fa.Agency == a {
+				fa.Agency = ref
+			}
+		}
+
+		delete(feed.Agencies, a.Id)
+	}
+}
+
+// urlString returns the string representation of an optional URL, or the
+// empty string if it is unset
+func urlString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	return u.String()
+}
+
+// langString returns the string representation of an optional language, or
+// the empty string if it is unset
+func langString(l *gtfs.Language) string {
+	if l == nil {
+		return ""
+	}
+
+	return string(*l)
+}
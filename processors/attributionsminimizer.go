@@ -0,0 +1,77 @@
+// Copyright 2016 Patrick Brosi
+// Authors: info@patrickbrosi.de
+//
+// Use of this source code is governed by a GPL v2
+// license that can be found in the LICENSE file
+
+package processors
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/patrickbr/gtfsparser"
+	gtfs "github.com/patrickbr/gtfsparser/gtfs"
+	"github.com/patrickbr/gtfstidy/processors/attrutil"
+)
+
+// AttributionsMinimizer collapses attribution records that are semantically
+// identical and point to the same entity
+type AttributionsMinimizer struct {
+}
+
+// Run this AttributionsMinimizer on some feed
+func (m AttributionsMinimizer) Run(feed *gtfsparser.Feed) {
+	fmt.Fprintf(os.Stdout, "Minimizing attributions... ")
+	bef := len(feed.Attributions)
+
+	// feed.Attributions is keyed by attribution_id, which is an optional
+	// GTFS field and may be empty or shared across records - look entries
+	// up by pointer instead of by that key
+	keyOf := make(map[*gtfs.Attribution]string, len(feed.Attributions))
+	for id, at := range feed.Attributions {
+		keyOf[at] = id
+	}
+
+	// dedup returns a copy of attrs with duplicates collapsed and drops any record that MergeAttributions
+	// considers redundant from feed.Attributions, leaving every other entry -
+	// including feed-level attributions reachable from none of the entities
+	// below - untouched
+	dedup := func(attrs []*gtfs.Attribution) []*gtfs.Attribution {
+		merged := attrutil.MergeAttributions(attrs, nil)
+
+		if len(merged) == len(attrs) {
+			return merged
+		}
+
+		kept := make(map[*gtfs.Attribution]bool, len(merged))
+		for _, at := range merged {
+			kept[at] = true
+		}
+
+		for _, at := range attrs {
+			if kept[at] {
+				continue
+			}
+			if id, ok := keyOf[at]; ok {
+				delete(feed.Attributions, id)
+			}
+		}
+
+		return merged
+	}
+
+	for _, a := range feed.Agencies {
+		a.Attributions = dedup(a.Attributions)
+	}
+
+	for _, r := range feed.Routes {
+		r.Attributions = dedup(r.Attributions)
+	}
+
+	for _, t := range feed.Trips {
+		t.Attributions = dedup(t.Attributions)
+	}
+
+	fmt.Fprintf(os.Stdout, "done. (-%d attributions)\n", (bef - len(feed.Attributions)))
+}
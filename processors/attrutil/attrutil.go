@@ -0,0 +1,58 @@
+// Copyright 2016 Patrick Brosi
+// Authors: info@patrickbrosi.de
+//
+// Use of this source code is governed by a GPL v2
+// license that can be found in the LICENSE file
+
+// Package attrutil provides helpers for merging attributions.txt records
+// that are shared by the various duplicate removers
+package attrutil
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+
+	gtfs "github.com/patrickbr/gtfsparser/gtfs"
+)
+
+// MergeAttributions combines two slices of attributions into one, dropping
+// entries that describe the same organization and role more than once.
+// Either slice may be nil.
+func MergeAttributions(a []*gtfs.Attribution, b []*gtfs.Attribution) []*gtfs.Attribution {
+	seen := make(map[uint64]bool, len(a)+len(b))
+	ret := make([]*gtfs.Attribution, 0, len(a)+len(b))
+
+	for _, attrs := range [][]*gtfs.Attribution{a, b} {
+		for _, at := range attrs {
+			h := hash(at)
+			if seen[h] {
+				continue
+			}
+			seen[h] = true
+			ret = append(ret, at)
+		}
+	}
+
+	return ret
+}
+
+// hash computes a stable identity hash for an attribution record
+func hash(at *gtfs.Attribution) uint64 {
+	h := fnv.New64a()
+
+	fmt.Fprintf(h, "%s|%t|%t|%t|%s|%s|%s",
+		at.Organization_name, at.Is_producer, at.Is_operator, at.Is_authority, urlString(at.Url), at.Email, at.Phone)
+
+	return h.Sum64()
+}
+
+// urlString returns the string representation of an optional URL, or the
+// empty string if it is unset
+func urlString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	return u.String()
+}
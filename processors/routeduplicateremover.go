@@ -8,9 +8,12 @@ package processors
 
 import (
 	"fmt"
+	"hash/fnv"
+	"os"
+
 	"github.com/patrickbr/gtfsparser"
 	gtfs "github.com/patrickbr/gtfsparser/gtfs"
-	"os"
+	"github.com/patrickbr/gtfstidy/processors/attrutil"
 )
 
 // RouteDuplicateRemover merges semantically equivalent routes
@@ -21,38 +24,59 @@ type RouteDuplicateRemover struct {
 func (m RouteDuplicateRemover) Run(feed *gtfsparser.Feed) {
 	fmt.Fprintf(os.Stdout, "Removing redundant routes... ")
 	var idCount int64 = 1 // counter for new ids
-	proced := make(map[*gtfs.Route]bool, len(feed.Routes))
 	bef := len(feed.Routes)
 
-	numchunks := MaxParallelism()
-	chunksize := (len(feed.Routes) + numchunks - 1) / numchunks
-	chunks := make([][]*gtfs.Route, numchunks)
-	curchunk := 0
-
 	trips := make(map[*gtfs.Route][]*gtfs.Trip, len(feed.Routes))
 
-	for _, r := range feed.Routes {
-		chunks[curchunk] = append(chunks[curchunk], r)
-		if len(chunks[curchunk]) == chunksize {
-			curchunk++
-		}
-	}
-
 	for _, t := range feed.Trips {
 		trips[t.Route] = append(trips[t.Route], t)
 	}
 
+	// pre-index fare rules by route once, instead of scanning all fare
+	// attributes for every comparison
+	fareIdx := make(map[*gtfs.Route][]*gtfs.FareAttributeRule, len(feed.Routes))
+	ruleAttr := make(map[*gtfs.FareAttributeRule]*gtfs.FareAttribute)
+
+	for _, fa := range feed.FareAttributes {
+		for _, fr := range fa.Rules {
+			fareIdx[fr.Route] = append(fareIdx[fr.Route], fr)
+			ruleAttr[fr] = fa
+		}
+	}
+
+	buckets := make(map[uint64][]*gtfs.Route, len(feed.Routes))
+
 	for _, r := range feed.Routes {
-		if _, ok := proced[r]; ok {
+		h := m.routeHash(r)
+		buckets[h] = append(buckets[h], r)
+	}
+
+	for _, bucket := range buckets {
+		if len(bucket) < 2 {
 			continue
 		}
-		eqRoutes := m.getEquivalentRoutes(r, feed, chunks)
 
-		if len(eqRoutes) > 0 {
-			m.combineRoutes(feed, append(eqRoutes, r), trips, &idCount)
+		proced := make(map[*gtfs.Route]bool, len(bucket))
 
-			for _, r := range eqRoutes {
-				proced[r] = true
+		for _, r := range bucket {
+			if proced[r] {
+				continue
+			}
+
+			eqRoutes := make([]*gtfs.Route, 0)
+
+			for _, cand := range bucket {
+				if cand != r && !proced[cand] && m.routeEquals(cand, r) && m.checkFareEquality(fareIdx, ruleAttr, r, cand) {
+					eqRoutes = append(eqRoutes, cand)
+				}
+			}
+
+			if len(eqRoutes) > 0 {
+				m.combineRoutes(feed, append(eqRoutes, r), trips, &idCount)
+
+				for _, cand := range eqRoutes {
+					proced[cand] = true
+				}
 			}
 
 			proced[r] = true
@@ -62,49 +86,32 @@ func (m RouteDuplicateRemover) Run(feed *gtfsparser.Feed) {
 	fmt.Fprintf(os.Stdout, "done. (-%d routes)\n", (bef - len(feed.Routes)))
 }
 
-// Returns the feed's routes that are equivalent to route
-func (m RouteDuplicateRemover) getEquivalentRoutes(route *gtfs.Route, feed *gtfsparser.Feed, chunks [][]*gtfs.Route) []*gtfs.Route {
-	rets := make([][]*gtfs.Route, len(chunks))
-	sem := make(chan empty, len(chunks))
+// Compute a stable identity hash for a route
+func (m RouteDuplicateRemover) routeHash(r *gtfs.Route) uint64 {
+	h := fnv.New64a()
 
-	for i, c := range chunks {
-		go func(j int, chunk []*gtfs.Route) {
-			for _, r := range chunk {
-				if r != route && m.routeEquals(r, route) && m.checkFareEquality(feed, route, r) {
-					rets[j] = append(rets[j], r)
-				}
-			}
-			sem <- empty{}
-		}(i, c)
-	}
+	fmt.Fprintf(h, "%p|%s|%s|%s|%d|%s|%s|%s",
+		r.Agency, r.Short_name, r.Long_name, r.Desc, r.Type, urlString(r.Url), r.Color, r.Text_color)
 
-	// wait for goroutines to finish
-	for i := 0; i < len(chunks); i++ {
-		<-sem
-	}
+	return h.Sum64()
+}
 
-	// combine results
-	ret := make([]*gtfs.Route, 0)
+// Check if two routes are equal regarding the fares, using the pre-built
+// route -> fare rule index
+func (m RouteDuplicateRemover) checkFareEquality(fareIdx map[*gtfs.Route][]*gtfs.FareAttributeRule, ruleAttr map[*gtfs.FareAttributeRule]*gtfs.FareAttribute, a *gtfs.Route, b *gtfs.Route) bool {
+	seen := make(map[*gtfs.FareAttribute]bool)
 
-	for _, r := range rets {
-		ret = append(ret, r...)
-	}
+	for _, rules := range [][]*gtfs.FareAttributeRule{fareIdx[a], fareIdx[b]} {
+		for _, fr := range rules {
+			fa := ruleAttr[fr]
 
-	return ret
-}
+			if seen[fa] {
+				continue
+			}
+			seen[fa] = true
 
-// Check if two routes are equal regarding the fares
-func (m RouteDuplicateRemover) checkFareEquality(feed *gtfsparser.Feed, a *gtfs.Route, b *gtfs.Route) bool {
-	for _, fa := range feed.FareAttributes {
-		// check if this rule contains route a
-		for _, fr := range fa.Rules {
-			if fr.Route == a || fr.Route == b {
-				// if so,
-				if !m.fareRulesEqual(fa, a, b) {
-					return false
-				}
-				// go on to the next FareClass
-				break
+			if !m.fareRulesEqual(fa, a, b) {
+				return false
 			}
 		}
 	}
@@ -177,6 +184,8 @@ func (m RouteDuplicateRemover) combineRoutes(feed *gtfsparser.Feed, routes []*gt
 			continue
 		}
 
+		ref.Attributions = attrutil.MergeAttributions(ref.Attributions, r.Attributions)
+
 		for _, t := range trips[r] {
 			if t.Route == r {
 				t.Route = ref
@@ -0,0 +1,179 @@
+// Copyright 2016 Patrick Brosi
+// Authors: info@patrickbrosi.de
+//
+// Use of this source code is governed by a GPL v2
+// license that can be found in the LICENSE file
+
+package processors
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/patrickbr/gtfsparser"
+	gtfs "github.com/patrickbr/gtfsparser/gtfs"
+)
+
+// earthRadius in meters, used for haversine distance calculation
+const earthRadius = 6371000.0
+
+// StopDuplicateRemover merges semantically equivalent stops that lie within
+// MaxEqDistance meters of each other
+type StopDuplicateRemover struct {
+	MaxEqDistance float64
+}
+
+// gridCell identifies a cell in the spatial bucketing grid
+type gridCell struct {
+	x int64
+	y int64
+}
+
+// Run this StopDuplicateRemover on some feed
+func (m StopDuplicateRemover) Run(feed *gtfsparser.Feed) {
+	fmt.Fprintf(os.Stdout, "Removing redundant stops... ")
+	bef := len(feed.Stops)
+
+	cellSize := m.MaxEqDistance
+	if cellSize <= 0 {
+		cellSize = 1.0
+	}
+
+	grid := make(map[gridCell][]*gtfs.Stop, len(feed.Stops))
+
+	for _, s := range feed.Stops {
+		grid[m.cellFor(s, cellSize)] = append(grid[m.cellFor(s, cellSize)], s)
+	}
+
+	proced := make(map[*gtfs.Stop]bool, len(feed.Stops))
+
+	for _, s := range feed.Stops {
+		if proced[s] {
+			continue
+		}
+
+		eqStops := make([]*gtfs.Stop, 0)
+		c := m.cellFor(s, cellSize)
+
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for _, cand := range grid[gridCell{c.x + dx, c.y + dy}] {
+					if cand != s && !proced[cand] && m.stopEquals(s, cand) {
+						eqStops = append(eqStops, cand)
+					}
+				}
+			}
+		}
+
+		if len(eqStops) > 0 {
+			m.combineStops(feed, append(eqStops, s))
+
+			for _, cand := range eqStops {
+				proced[cand] = true
+			}
+		}
+
+		proced[s] = true
+	}
+
+	fmt.Fprintf(os.Stdout, "done. (-%d stops)\n", (bef - len(feed.Stops)))
+}
+
+// cellFor returns the grid cell a stop falls into, given a cell size in meters
+func (m StopDuplicateRemover) cellFor(s *gtfs.Stop, cellSize float64) gridCell {
+	latMeters := s.Lat * (math.Pi / 180) * earthRadius
+	lonMeters := s.Lon * (math.Pi / 180) * earthRadius * math.Cos(s.Lat*math.Pi/180)
+
+	return gridCell{int64(math.Floor(latMeters / cellSize)), int64(math.Floor(lonMeters / cellSize))}
+}
+
+// Check if two stops are semantically equal
+func (m StopDuplicateRemover) stopEquals(a *gtfs.Stop, b *gtfs.Stop) bool {
+	if a.Name != b.Name || a.Parent_station != b.Parent_station || a.Location_type != b.Location_type ||
+		a.Wheelchair_boarding != b.Wheelchair_boarding || a.Code != b.Code || a.Desc != b.Desc ||
+		a.Timezone != b.Timezone || urlString(a.Url) != urlString(b.Url) {
+		return false
+	}
+
+	return m.haversine(a.Lat, a.Lon, b.Lat, b.Lon) <= m.MaxEqDistance
+}
+
+// haversine returns the great-circle distance between two lat/lon points, in meters
+func (m StopDuplicateRemover) haversine(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	rlat1 := lat1 * math.Pi / 180
+	rlat2 := lat2 * math.Pi / 180
+	dLat := rlat2 - rlat1
+	dLon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rlat1)*math.Cos(rlat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	return earthRadius * c
+}
+
+// Combine a slice of equal stops into a single stop
+func (m StopDuplicateRemover) combineStops(feed *gtfsparser.Feed, stops []*gtfs.Stop) {
+	// heuristic: use the stop with the shortest ID as 'reference'
+	ref := stops[0]
+
+	for _, s := range stops {
+		if len(s.Id) < len(ref.Id) {
+			ref = s
+		}
+	}
+
+	for _, s := range stops {
+		if s == ref {
+			continue
+		}
+
+		for _, t := range feed.Trips {
+			for _, st := range t.StopTimes {
+				if st.Stop == s {
+					st.Stop = ref
+				}
+			}
+		}
+
+		for _, tr := range feed.Transfers {
+			if tr.From_stop == s {
+				tr.From_stop = ref
+			}
+			if tr.To_stop == s {
+				tr.To_stop = ref
+			}
+		}
+
+		for _, pw := range feed.Pathways {
+			if pw.From_stop == s {
+				pw.From_stop = ref
+			}
+			if pw.To_stop == s {
+				pw.To_stop = ref
+			}
+		}
+
+		for _, fa := range feed.FareAttributes {
+			for _, fr := range fa.Rules {
+				if fr.Origin_id == s.Id {
+					fr.Origin_id = ref.Id
+				}
+				if fr.Destination_id == s.Id {
+					fr.Destination_id = ref.Id
+				}
+				if fr.Contains_id == s.Id {
+					fr.Contains_id = ref.Id
+				}
+			}
+		}
+
+		for _, other := range feed.Stops {
+			if other.Parent_station == s {
+				other.Parent_station = ref
+			}
+		}
+
+		delete(feed.Stops, s.Id)
+	}
+}